@@ -3,10 +3,13 @@ package main
 import (
 	"crypto/rand"
 	"encoding/csv"
+	"flag"
 	"fmt"
 	"math"
+	mrand "math/rand"
 	"os"
 	"runtime"
+	"sort"
 	"time"
 
 	rs "github.com/klauspost/reedsolomon"
@@ -20,43 +23,79 @@ import (
 //
 
 // ---------- XOR ----------
+// EncodeXOR returns N data shards followed by `parity` identical XOR-parity
+// shards (each the XOR of all data shards), so the layout matches RS/RaptorQ
+// and a loss mask can be taken over the full N+parity shard set.
 func EncodeXOR(payload []byte, symbolSize, parity int) ([][]byte, error) {
 	k := int(math.Ceil(float64(len(payload)) / float64(symbolSize)))
-	parities := make([][]byte, parity)
+	shards := make([][]byte, k+parity)
+	parityBuf := make([]byte, symbolSize)
+	for i := 0; i < k; i++ {
+		start := i * symbolSize
+		end := start + symbolSize
+		if end > len(payload) {
+			end = len(payload)
+		}
+		shard := make([]byte, symbolSize)
+		copy(shard, payload[start:end])
+		shards[i] = shard
+		for j := 0; j < end-start; j++ {
+			parityBuf[j] ^= payload[start+j]
+		}
+	}
 	for p := 0; p < parity; p++ {
 		buf := make([]byte, symbolSize)
-		for i := 0; i < k; i++ {
-			start := i * symbolSize
-			end := start + symbolSize
-			if end > len(payload) {
-				end = len(payload)
-			}
-			for j := 0; j < end-start; j++ {
-				buf[j] ^= payload[start+j]
-			}
-		}
-		parities[p] = buf
+		copy(buf, parityBuf)
+		shards[k+p] = buf
 	}
-	return parities, nil
+	return shards, nil
 }
 
-func DecodeXOR(dataShards [][]byte, lostIndices []int) error {
-	if len(lostIndices) == 0 {
-		return nil
+// DecodeXOR recovers a single lost data shard by XOR-ing every surviving
+// data shard against a surviving parity shard. A single XOR parity can only
+// repair one lost data shard per block, so losing more than one is reported
+// as an unrecoverable error rather than silently producing garbage; lost
+// parity shards are harmless as long as at least one parity survives.
+// It returns the number of data shards (indices [0,k)) still missing once
+// it gives up, which is 0 on success.
+func DecodeXOR(shards [][]byte, lostIndices []int, k int) (int, error) {
+	lostData := []int{}
+	for _, idx := range lostIndices {
+		if idx < k {
+			lostData = append(lostData, idx)
+		}
 	}
-	k := len(dataShards)
-	symbolSize := len(dataShards[0])
+	if len(lostData) == 0 {
+		return 0, nil
+	}
+	if len(lostData) > 1 {
+		return len(lostData), fmt.Errorf("XOR cannot recover %d simultaneous data-shard losses from a single parity", len(lostData))
+	}
+
+	parityIdx := -1
+	for i := k; i < len(shards); i++ {
+		if shards[i] != nil {
+			parityIdx = i
+			break
+		}
+	}
+	if parityIdx == -1 {
+		return len(lostData), fmt.Errorf("no surviving parity shard to recover from")
+	}
+
+	symbolSize := len(shards[parityIdx])
 	recoverBuf := make([]byte, symbolSize)
+	copy(recoverBuf, shards[parityIdx])
 	for i := 0; i < k; i++ {
-		if i == lostIndices[0] {
+		if i == lostData[0] || shards[i] == nil {
 			continue
 		}
 		for j := 0; j < symbolSize; j++ {
-			recoverBuf[j] ^= dataShards[i][j]
+			recoverBuf[j] ^= shards[i][j]
 		}
 	}
-	copy(dataShards[lostIndices[0]], recoverBuf)
-	return nil
+	shards[lostData[0]] = recoverBuf
+	return 0, nil
 }
 
 // ---------- Reed–Solomon ----------
@@ -90,28 +129,43 @@ func EncodeRS(payload []byte, symbolSize, parity int) ([][]byte, error) {
 	return shards, nil
 }
 
-func DecodeRS(shards [][]byte, parity int) error {
+// DecodeRS reconstructs erased shards in place and returns the number of
+// data shards (indices [0,dataShards)) still missing once it gives up,
+// which is 0 on success. Reconstruct is all-or-nothing: it either fills
+// every erasure or leaves the block untouched, so on failure the missing
+// count is exactly what was erased going in.
+func DecodeRS(shards [][]byte, parity int) (int, error) {
 	total := len(shards)
 	dataShards := total - parity
 	if dataShards <= 0 {
-		return fmt.Errorf("invalid shard config: data=%d parity=%d", dataShards, parity)
+		return dataCount(shards, dataShards), fmt.Errorf("invalid shard config: data=%d parity=%d", dataShards, parity)
 	}
 
+	lostData := dataCount(shards, dataShards)
+
 	dec, err := rs.New(dataShards, parity)
 	if err != nil {
-		return err
+		return lostData, err
 	}
 
-	// shards[0] = nil // simulate one missing data shard
-
-	ok, err := dec.Verify(shards)
-	if err != nil {
-		return err
+	// Reconstruct both verifies and fills in erasures; Verify alone returns
+	// ErrShardSize as soon as a shard is nil, which would make Reconstruct
+	// unreachable on every lossy iteration.
+	if err := dec.Reconstruct(shards); err != nil {
+		return lostData, err
 	}
-	if ok {
-		return nil
+	return 0, nil
+}
+
+// dataCount returns the number of nil shards among indices [0,dataShards).
+func dataCount(shards [][]byte, dataShards int) int {
+	n := 0
+	for i := 0; i < dataShards && i < len(shards); i++ {
+		if shards[i] == nil {
+			n++
+		}
 	}
-	return dec.Reconstruct(shards)
+	return n
 }
 
 // ---------- RaptorQ ----------
@@ -131,25 +185,37 @@ func EncodeRaptorQ(payload []byte, symbolSize, parity int) ([][]byte, error) {
 	return out, nil
 }
 
-func DecodeRaptorQ(encoded [][]byte, symbolSize int, dataSizeBytes int) error {
+// DecodeRaptorQ decodes the block and returns the number of data shards
+// (systematic indices [0,K)) still missing once it gives up, which is 0 on
+// success. Like RS, RaptorQ decode is all-or-nothing over the whole block,
+// so on failure the missing count is exactly what was erased going in.
+func DecodeRaptorQ(encoded [][]byte, symbolSize int, dataSizeBytes int) (int, error) {
+	K := int(math.Ceil(float64(dataSizeBytes) / float64(symbolSize)))
+	lostData := dataCount(encoded, K)
+
 	r := rq.NewRaptorQ(uint32(symbolSize))
 	dec, err := r.CreateDecoder(uint32(dataSizeBytes))
 	if err != nil {
-		return err
+		return lostData, err
 	}
 
 	for i, sym := range encoded {
+		if sym == nil {
+			continue
+		}
 		done, err := dec.AddSymbol(uint32(i), sym)
 		if err != nil {
-			return err
+			return lostData, err
 		}
 		if done {
 			break
 		}
 	}
 
-	_, _, err = dec.Decode()
-	return err
+	if _, _, err := dec.Decode(); err != nil {
+		return lostData, err
+	}
+	return 0, nil
 }
 
 //
@@ -169,6 +235,24 @@ type Result struct {
 	DecSec     float64
 	EncPerByte float64
 	DecPerByte float64
+
+	LossModel           string
+	LossParams          string
+	RecoverySuccessRate float64
+	ResidualLossRate    float64
+	MeanLostShards      float64
+	P95LostShards       float64
+
+	GoodputMbps float64
+	RetxCount   int
+
+	Threads       int
+	SIMD          string
+	ThroughputGBs float64
+
+	Checksum               string
+	ChecksumOverheadPct    float64
+	CorruptionRecoveryRate float64
 }
 
 func mean(xs []float64) float64 {
@@ -182,18 +266,55 @@ func mean(xs []float64) float64 {
 	return sum / float64(len(xs))
 }
 
+func percentile95(xs []float64) float64 {
+	if len(xs) == 0 {
+		return 0
+	}
+	sorted := append([]float64{}, xs...)
+	sort.Float64s(sorted)
+	idx := int(math.Ceil(0.95*float64(len(sorted)))) - 1
+	if idx < 0 {
+		idx = 0
+	}
+	if idx >= len(sorted) {
+		idx = len(sorted) - 1
+	}
+	return sorted[idx]
+}
+
+// decodeFunc is fed the encoded shards with erased entries set to nil, plus
+// the list of indices that were erased, and attempts to reconstruct them
+// in place. It returns the number of data shards still missing once it
+// gives up (0 on full success) alongside the usual error, since peeling
+// decoders like LT and LDPC-staircase can recover some data shards before
+// stalling on the rest.
+type decodeFunc func(shards [][]byte, lostIndices []int) (unrecoveredData int, err error)
+
 func bench(algo string,
 	encode func([]byte, int, int) ([][]byte, error),
-	decode func([][]byte, int) error,
-	N, S int, R float64, iters int) Result {
+	decode decodeFunc,
+	N, S int, R float64, iters int, loss LossModel, checksum Checksum, corruptP float64) Result {
 
 	payloadB := N * S
 	parity := int(math.Ceil(float64(N) * R / 100.0))
+	total := N + parity
 
 	payload := make([]byte, payloadB)
 	_, _ = rand.Read(payload)
 
-	var encTimes, decTimes []float64
+	rng := mrand.New(mrand.NewSource(time.Now().UnixNano()))
+
+	// Warm up the encoder/decoder (JIT-ish effects in the underlying C-like
+	// SIMD paths, allocator warmup) before the timed iterations, so the
+	// first call doesn't skew small-payload measurements.
+	if _, err := encode(payload, S, parity); err != nil {
+		fmt.Printf("%s warmup error: %v\n", algo, err)
+		return Result{}
+	}
+
+	var encNs, decNs, lostCounts, residualCounts []float64
+	recovered := 0
+	corruptedIters, corruptedRecovered := 0, 0
 	for i := 0; i < iters; i++ {
 		start := time.Now()
 		shards, err := encode(payload, S, parity)
@@ -201,38 +322,117 @@ func bench(algo string,
 			fmt.Printf("%s encode error: %v\n", algo, err)
 			return Result{}
 		}
-		encTimes = append(encTimes, time.Since(start).Seconds())
+		encNs = append(encNs, float64(time.Since(start).Nanoseconds()))
+
+		if decode == nil {
+			continue
+		}
+
+		mask := loss.Mask(total, rng)
+		var lostIndices []int
+		for idx, lost := range mask {
+			if lost && idx < len(shards) {
+				lostIndices = append(lostIndices, idx)
+				shards[idx] = nil
+			}
+		}
+
+		corruptedThisIter := false
+		for idx, shard := range shards {
+			if shard == nil {
+				continue
+			}
+			withTrailer := Append(checksum, shard)
+			if corruptP > 0 && rng.Float64() < corruptP {
+				corruptedThisIter = true
+				flipRandomBit(withTrailer, rng)
+			}
+			data, ok := VerifyAndStrip(checksum, withTrailer)
+			if !ok {
+				shards[idx] = nil
+				lostIndices = append(lostIndices, idx)
+				continue
+			}
+			shards[idx] = data
+		}
+		lostCounts = append(lostCounts, float64(len(lostIndices)))
 
-		if decode != nil {
-			start = time.Now()
-			if err := decode(shards, parity); err != nil {
-				fmt.Printf("%s decode error: %v\n", algo, err)
-				return Result{}
+		start = time.Now()
+		unrecoveredData, decErr := decode(shards, lostIndices)
+		decNs = append(decNs, float64(time.Since(start).Nanoseconds()))
+		if decErr == nil {
+			recovered++
+		}
+		residualCounts = append(residualCounts, float64(unrecoveredData))
+		if corruptedThisIter {
+			corruptedIters++
+			if decErr == nil {
+				corruptedRecovered++
 			}
-			decTimes = append(decTimes, time.Since(start).Seconds())
 		}
 	}
 
-	meanEnc := mean(encTimes)
-	meanDec := mean(decTimes)
+	meanEnc := mean(encNs) / 1e9
+	meanDec := mean(decNs) / 1e9
 	tByteEnc := meanEnc / float64(payloadB)
 	tByteDec := meanDec / float64(payloadB)
 
-	fmt.Printf("%-12s | N=%4d S=%5d R=%4.1f%% | Enc=%.6fs (%.3f ns/B) | Dec=%.6fs (%.3f ns/B)\n",
-		algo, N, S, R, meanEnc, tByteEnc*1e9, meanDec, tByteDec*1e9)
+	recoveryRate := 0.0
+	if decode != nil && iters > 0 {
+		recoveryRate = float64(recovered) / float64(iters)
+	}
+	// 0 when -corrupt wasn't exercised this run, rather than the unrelated
+	// overall recoveryRate, so sweeps without corruption don't misread the
+	// column as a corruption-recovery measurement.
+	corruptionRecoveryRate := 0.0
+	if corruptedIters > 0 {
+		corruptionRecoveryRate = float64(corruptedRecovered) / float64(corruptedIters)
+	}
+
+	// Fraction of data shards still missing after decode, averaged across
+	// iterations — distinct from recoveryRate (block pass/fail): a peeling
+	// decoder (LT, LDPC) that recovers some but not all data shards before
+	// stalling contributes a partial fraction here rather than the full
+	// erasure count, and lost parity shards (which carry no application
+	// data) don't count against it at all.
+	residualLossRate := mean(residualCounts) / float64(N)
+
+	fmt.Printf("%-12s | N=%4d S=%5d R=%4.1f%% loss=%s(%s) | Enc=%.6fs (%.3f ns/B) | Dec=%.6fs (%.3f ns/B) | Recovery=%.1f%%\n",
+		algo, N, S, R, loss.Name(), loss.Params(), meanEnc, tByteEnc*1e9, meanDec, tByteDec*1e9, recoveryRate*100)
 
 	return Result{
-		Algo:       algo,
-		N:          N,
-		S:          S,
-		R:          R,
-		Parity:     parity,
-		PayloadB:   payloadB,
-		EncSec:     meanEnc,
-		DecSec:     meanDec,
-		EncPerByte: tByteEnc,
-		DecPerByte: tByteDec,
+		Algo:                algo,
+		N:                   N,
+		S:                   S,
+		R:                   R,
+		Parity:              parity,
+		PayloadB:            payloadB,
+		EncSec:              meanEnc,
+		DecSec:              meanDec,
+		EncPerByte:          tByteEnc,
+		DecPerByte:          tByteDec,
+		LossModel:           loss.Name(),
+		LossParams:          loss.Params(),
+		RecoverySuccessRate: recoveryRate,
+		ResidualLossRate:    residualLossRate,
+		MeanLostShards:      mean(lostCounts),
+		P95LostShards:       percentile95(lostCounts),
+
+		Checksum:               checksum.Name(),
+		ChecksumOverheadPct:    float64(checksum.Size()) / float64(S) * 100,
+		CorruptionRecoveryRate: corruptionRecoveryRate,
+	}
+}
+
+// flipRandomBit flips a single random bit in place, simulating the kind of
+// transient bit error a checksum trailer is meant to catch.
+func flipRandomBit(buf []byte, rng *mrand.Rand) {
+	if len(buf) == 0 {
+		return
 	}
+	byteIdx := rng.Intn(len(buf))
+	bitIdx := rng.Intn(8)
+	buf[byteIdx] ^= 1 << uint(bitIdx)
 }
 
 //
@@ -242,24 +442,61 @@ func bench(algo string,
 //
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "quic" {
+		runQuicCmd(os.Args[2:])
+		return
+	}
+	runBench(os.Args[1:])
+}
+
+func runBench(args []string) {
+	fs := flag.NewFlagSet("bench", flag.ExitOnError)
+	parallelSweep := fs.Bool("parallel", false, "additionally sweep GOMAXPROCS 1..NumCPU and report aggregate throughput")
+	simdMode := fs.String("simd", "auto", "reedsolomon SIMD mode used by the -parallel sweep: auto, none, avx2, avx512")
+	checksumName := fs.String("checksum", "crc32c", "shard integrity checksum: crc32c, crc64, blake3-128")
+	corruptP := fs.Float64("corrupt", 0, "probability of flipping a random bit in a surviving shard before decode")
+	fs.Parse(args)
+
+	checksum := NewChecksum(*checksumName)
+
+	if *parallelSweep {
+		results := runParallelThroughputSweep(*simdMode)
+		writeCSV("results_parallel.csv", results)
+		return
+	}
+
 	runtime.GOMAXPROCS(1)
 	results := []Result{}
 
 	Ns := []int{10, 20, 30, 40, 50}     // number of source symbols
 	Ss := []int{64, 92, 120, 250, 512}  // bytes per symbol
 	Rs := []float64{10, 20, 30, 40, 50} // redundancy %
-	iters := 3
+	iters := 25                         // averaged over more runs for stable small-payload timings
+
+	lossModelFactories := []func() LossModel{
+		func() LossModel { return &UniformLossModel{P: 0.02} },
+		func() LossModel { return &UniformLossModel{P: 0.10} },
+		func() LossModel { return &GilbertElliottLossModel{P: 0.05, R: 0.5, K: 1.0, H: 0.2} },
+	}
 
 	for _, N := range Ns {
 		for _, S := range Ss {
 			for _, R := range Rs {
-				xorDecode := func(sh [][]byte, _ int) error { return DecodeXOR(sh, []int{0}) }
-				rsDecode := func(sh [][]byte, _ int) error { return DecodeRS(sh, int(math.Ceil(float64(N)*R/100.0))) }
-				rqDecode := func(sh [][]byte, _ int) error { return DecodeRaptorQ(sh, S, N*S) }
-
-				results = append(results, bench("XOR", EncodeXOR, xorDecode, N, S, R, iters))
-				results = append(results, bench("ReedSolomon", EncodeRS, rsDecode, N, S, R, iters))
-				results = append(results, bench("RaptorQ", EncodeRaptorQ, rqDecode, N, S, R, iters))
+				parity := int(math.Ceil(float64(N) * R / 100.0))
+
+				xorDecode := func(sh [][]byte, lost []int) (int, error) { return DecodeXOR(sh, lost, N) }
+				rsDecode := func(sh [][]byte, lost []int) (int, error) { return DecodeRS(sh, parity) }
+				rqDecode := func(sh [][]byte, lost []int) (int, error) { return DecodeRaptorQ(sh, S, N*S) }
+				ltDecode := func(sh [][]byte, lost []int) (int, error) { return DecodeLT(sh, lost, N) }
+				ldpcDecode := func(sh [][]byte, lost []int) (int, error) { return DecodeLDPCStaircase(sh, lost, N, parity) }
+
+				for _, newLoss := range lossModelFactories {
+					results = append(results, bench("XOR", EncodeXOR, xorDecode, N, S, R, iters, newLoss(), checksum, *corruptP))
+					results = append(results, bench("ReedSolomon", EncodeRS, rsDecode, N, S, R, iters, newLoss(), checksum, *corruptP))
+					results = append(results, bench("RaptorQ", EncodeRaptorQ, rqDecode, N, S, R, iters, newLoss(), checksum, *corruptP))
+					results = append(results, bench("LT-Fountain", EncodeLT, ltDecode, N, S, R, iters, newLoss(), checksum, *corruptP))
+					results = append(results, bench("LDPC-Staircase", EncodeLDPCStaircase, ldpcDecode, N, S, R, iters, newLoss(), checksum, *corruptP))
+				}
 			}
 		}
 	}
@@ -285,7 +522,11 @@ func writeCSV(filename string, results []Result) {
 	defer w.Flush()
 
 	w.Write([]string{"Algorithm", "N", "S", "R%", "Parity", "PayloadBytes",
-		"MeanEncSec", "MeanDecSec", "EncPerByte(s)", "DecPerByte(s)"})
+		"MeanEncSec", "MeanDecSec", "EncPerByte(s)", "DecPerByte(s)",
+		"LossModel", "LossParams", "RecoverySuccessRate", "ResidualLossRate",
+		"MeanLostShards", "P95LostShards", "GoodputMbps", "RetxCount",
+		"Threads", "SIMD", "ThroughputGBs",
+		"Checksum", "ChecksumOverheadPct", "CorruptionRecoveryRate"})
 
 	for _, r := range results {
 		w.Write([]string{
@@ -299,6 +540,20 @@ func writeCSV(filename string, results []Result) {
 			fmt.Sprintf("%.9f", r.DecSec),
 			fmt.Sprintf("%.9e", r.EncPerByte),
 			fmt.Sprintf("%.9e", r.DecPerByte),
+			r.LossModel,
+			r.LossParams,
+			fmt.Sprintf("%.4f", r.RecoverySuccessRate),
+			fmt.Sprintf("%.4f", r.ResidualLossRate),
+			fmt.Sprintf("%.3f", r.MeanLostShards),
+			fmt.Sprintf("%.3f", r.P95LostShards),
+			fmt.Sprintf("%.3f", r.GoodputMbps),
+			fmt.Sprintf("%d", r.RetxCount),
+			fmt.Sprintf("%d", r.Threads),
+			r.SIMD,
+			fmt.Sprintf("%.3f", r.ThroughputGBs),
+			r.Checksum,
+			fmt.Sprintf("%.4f", r.ChecksumOverheadPct),
+			fmt.Sprintf("%.4f", r.CorruptionRecoveryRate),
 		})
 	}
 	fmt.Println("\n✅ Results written to", filename)
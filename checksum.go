@@ -0,0 +1,107 @@
+package main
+
+import (
+	"encoding/binary"
+	"hash/crc32"
+	"hash/crc64"
+
+	"lukechampine.com/blake3"
+)
+
+//
+// ====================================================
+// Shard integrity (pluggable checksums)
+// ====================================================
+//
+// Real QUIC-over-FEC pipelines don't hand corrupted bytes straight to the
+// FEC decoder: a shard that fails integrity verification in transit is
+// treated the same as one that was never delivered. Checksum appends a
+// small trailer to every shard on encode, and on decode a failed
+// verification turns that shard into an erasure before the FEC scheme ever
+// sees it.
+
+// Checksum computes and verifies a fixed-size trailer over shard bytes.
+type Checksum interface {
+	Name() string
+	Size() int
+	Sum(data []byte) []byte
+}
+
+// Append returns data with cs's checksum trailer appended.
+func Append(cs Checksum, data []byte) []byte {
+	return append(append([]byte{}, data...), cs.Sum(data)...)
+}
+
+// VerifyAndStrip checks the trailer appended by Append and, if it matches,
+// returns the original data with the trailer removed.
+func VerifyAndStrip(cs Checksum, withTrailer []byte) (data []byte, ok bool) {
+	n := len(withTrailer) - cs.Size()
+	if n < 0 {
+		return nil, false
+	}
+	data = withTrailer[:n]
+	trailer := withTrailer[n:]
+	want := cs.Sum(data)
+	if len(trailer) != len(want) {
+		return nil, false
+	}
+	for i := range want {
+		if trailer[i] != want[i] {
+			return nil, false
+		}
+	}
+	return data, true
+}
+
+// ---------- CRC32C (Castagnoli) ----------
+
+var crc32cTable = crc32.MakeTable(crc32.Castagnoli)
+
+type CRC32CChecksum struct{}
+
+func (CRC32CChecksum) Name() string { return "crc32c" }
+func (CRC32CChecksum) Size() int    { return 4 }
+func (CRC32CChecksum) Sum(data []byte) []byte {
+	buf := make([]byte, 4)
+	binary.BigEndian.PutUint32(buf, crc32.Checksum(data, crc32cTable))
+	return buf
+}
+
+// ---------- CRC64-ISO (ECMA) ----------
+
+var crc64ISOTable = crc64.MakeTable(crc64.ISO)
+
+type CRC64ISOChecksum struct{}
+
+func (CRC64ISOChecksum) Name() string { return "crc64-iso" }
+func (CRC64ISOChecksum) Size() int    { return 8 }
+func (CRC64ISOChecksum) Sum(data []byte) []byte {
+	buf := make([]byte, 8)
+	binary.BigEndian.PutUint64(buf, crc64.Checksum(data, crc64ISOTable))
+	return buf
+}
+
+// ---------- BLAKE3-128 (truncated) ----------
+
+type BLAKE3_128Checksum struct{}
+
+func (BLAKE3_128Checksum) Name() string { return "blake3-128" }
+func (BLAKE3_128Checksum) Size() int    { return 16 }
+func (BLAKE3_128Checksum) Sum(data []byte) []byte {
+	sum := blake3.Sum256(data)
+	return sum[:16]
+}
+
+// NewChecksum resolves the -checksum flag value to a Checksum implementation,
+// defaulting to CRC32C (the cheapest option, matching typical hardware-CRC
+// use in real transport integrity checks).
+func NewChecksum(name string) Checksum {
+	switch name {
+	case "crc64":
+		return CRC64ISOChecksum{}
+	case "blake3-128":
+		return BLAKE3_128Checksum{}
+	default:
+		return CRC32CChecksum{}
+	}
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+)
+
+//
+// ====================================================
+// LDPC-Staircase code (RFC 5170)
+// ====================================================
+//
+// Each parity symbol is the XOR of a small, deterministically-chosen subset
+// of source symbols plus the previous parity symbol (the "staircase"), per
+// RFC 5170 §5.2. That chain, together with the sparse source connections,
+// forms the Tanner graph's check nodes; decoding is the same iterative
+// peeling used for LT, just over a fixed rather than randomly-drawn set of
+// check equations.
+
+const ldpcCheckDegree = 3 // source symbols folded into each parity check, excluding the staircase link
+
+// ldpcSources deterministically picks the source symbols check equation p
+// depends on, seeded on p so the decoder can recompute it without any
+// transmitted matrix.
+func ldpcSources(p, k int) []int {
+	d := ldpcCheckDegree
+	if d > k {
+		d = k
+	}
+	rng := rand.New(rand.NewSource(int64(p)*7919 + 1))
+	return rng.Perm(k)[:d]
+}
+
+func EncodeLDPCStaircase(payload []byte, symbolSize, parity int) ([][]byte, error) {
+	k := int(math.Ceil(float64(len(payload)) / float64(symbolSize)))
+	shards := make([][]byte, k+parity)
+	for i := 0; i < k; i++ {
+		start := i * symbolSize
+		end := start + symbolSize
+		if end > len(payload) {
+			end = len(payload)
+		}
+		shard := make([]byte, symbolSize)
+		copy(shard, payload[start:end])
+		shards[i] = shard
+	}
+
+	for p := 0; p < parity; p++ {
+		buf := make([]byte, symbolSize)
+		for _, s := range ldpcSources(p, k) {
+			for j := 0; j < symbolSize; j++ {
+				buf[j] ^= shards[s][j]
+			}
+		}
+		if p > 0 {
+			prev := shards[k+p-1]
+			for j := 0; j < symbolSize; j++ {
+				buf[j] ^= prev[j]
+			}
+		}
+		shards[k+p] = buf
+	}
+	return shards, nil
+}
+
+// ldpcCheck is one parity-check equation: XOR-ing shards[variables] together
+// must equal zero. Variables are source indices [0,k) and parity indices
+// [k,k+parity).
+type ldpcCheck struct {
+	variables []int
+}
+
+func ldpcChecks(k, parity int) []ldpcCheck {
+	checks := make([]ldpcCheck, parity)
+	for p := 0; p < parity; p++ {
+		vars := append([]int{k + p}, ldpcSources(p, k)...)
+		if p > 0 {
+			vars = append(vars, k+p-1)
+		}
+		checks[p] = ldpcCheck{variables: vars}
+	}
+	return checks
+}
+
+// DecodeLDPCStaircase recovers erased shards by iterative message passing
+// over the Tanner graph: any check equation with exactly one erased
+// variable can solve for it directly (XOR every known variable together),
+// which is peeled off and can unlock further checks. It terminates early
+// once nothing is erased or a full pass makes no progress. It returns the
+// number of data shards (indices [0,k)) still unresolved when it stops,
+// which is 0 on success — peeling can recover some data shards even when
+// parity shards (or other data shards) are never resolved.
+func DecodeLDPCStaircase(shards [][]byte, lostIndices []int, k, parity int) (int, error) {
+	if len(lostIndices) == 0 {
+		return 0, nil
+	}
+	lost := make(map[int]bool, len(lostIndices))
+	for _, idx := range lostIndices {
+		lost[idx] = true
+	}
+	checks := ldpcChecks(k, parity)
+
+	remaining := len(lost)
+	for remaining > 0 {
+		progressed := false
+		for _, c := range checks {
+			unknown := -1
+			unknownCount := 0
+			for _, v := range c.variables {
+				if shards[v] == nil {
+					unknownCount++
+					unknown = v
+				}
+			}
+			if unknownCount != 1 {
+				continue
+			}
+
+			symbolSize := symbolSizeOf(shards)
+			buf := make([]byte, symbolSize)
+			for _, v := range c.variables {
+				if v == unknown {
+					continue
+				}
+				for j := 0; j < symbolSize; j++ {
+					buf[j] ^= shards[v][j]
+				}
+			}
+			shards[unknown] = buf
+			remaining--
+			progressed = true
+		}
+		if !progressed {
+			break
+		}
+	}
+
+	unrecoveredData := dataCount(shards, k)
+	if remaining > 0 {
+		return unrecoveredData, fmt.Errorf("LDPC-staircase decode stalled: %d shard(s) still unresolved", remaining)
+	}
+	return unrecoveredData, nil
+}
+
+func symbolSizeOf(shards [][]byte) int {
+	for _, sh := range shards {
+		if sh != nil {
+			return len(sh)
+		}
+	}
+	return 0
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+)
+
+//
+// ====================================================
+// Loss Models
+// ====================================================
+//
+
+// LossModel produces, for a sequence of n shards (data + parity), a boolean
+// erasure mask indicating which shards are lost in transit. Implementations
+// may keep internal state across calls to model loss correlated over time
+// (e.g. bursty loss), so a fresh instance should be used per benchmark run.
+type LossModel interface {
+	Name() string
+	Params() string
+	Mask(n int, rng *rand.Rand) []bool
+}
+
+// ---------- Uniform i.i.d. loss ----------
+
+// UniformLossModel drops each shard independently with probability P.
+type UniformLossModel struct {
+	P float64
+}
+
+func (m *UniformLossModel) Name() string   { return "uniform" }
+func (m *UniformLossModel) Params() string { return fmt.Sprintf("p=%.3f", m.P) }
+func (m *UniformLossModel) Mask(n int, rng *rand.Rand) []bool {
+	mask := make([]bool, n)
+	for i := range mask {
+		mask[i] = rng.Float64() < m.P
+	}
+	return mask
+}
+
+// ---------- Gilbert–Elliott bursty loss ----------
+
+// GilbertElliottLossModel is a two-state Markov loss model: the channel
+// alternates between a "good" state (loss probability 1-K) and a "bad"
+// state (loss probability 1-H), transitioning good->bad with probability P
+// and bad->good with probability R. This captures the burstiness real QUIC
+// paths see under congestion or Wi-Fi fading, which a uniform model misses.
+type GilbertElliottLossModel struct {
+	P, R float64 // good->bad, bad->good transition probabilities
+	K, H float64 // per-state delivery probabilities (loss = 1-K, 1-H)
+	bad  bool    // current state; starts good
+}
+
+func (m *GilbertElliottLossModel) Name() string { return "gilbert-elliott" }
+func (m *GilbertElliottLossModel) Params() string {
+	return fmt.Sprintf("p=%.3f r=%.3f k=%.3f h=%.3f", m.P, m.R, m.K, m.H)
+}
+
+func (m *GilbertElliottLossModel) Mask(n int, rng *rand.Rand) []bool {
+	mask := make([]bool, n)
+	for i := range mask {
+		deliverProb := m.K
+		if m.bad {
+			deliverProb = m.H
+		}
+		mask[i] = rng.Float64() >= deliverProb
+
+		if m.bad {
+			if rng.Float64() < m.R {
+				m.bad = false
+			}
+		} else {
+			if rng.Float64() < m.P {
+				m.bad = true
+			}
+		}
+	}
+	return mask
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+)
+
+//
+// ====================================================
+// LT (Luby Transform) fountain code
+// ====================================================
+//
+// Encoded symbol i's degree and source neighbours are drawn from a PRNG
+// seeded with i, so the decoder can recompute the exact same neighbour set
+// from the symbol's index alone without any side-channel metadata — the
+// same trick real LT/Raptor implementations use to avoid shipping a
+// parity-check matrix over the wire.
+
+const (
+	ltRobustC     = 0.1
+	ltRobustDelta = 0.5
+)
+
+// robustSolitonDist returns the Robust Soliton probability mass function
+// over degrees 1..k for k source symbols, parameterized by c and delta.
+func robustSolitonDist(k int, c, delta float64) []float64 {
+	rho := make([]float64, k+1)
+	rho[1] = 1.0 / float64(k)
+	for d := 2; d <= k; d++ {
+		rho[d] = 1.0 / (float64(d) * float64(d-1))
+	}
+
+	s := c * math.Log(float64(k)/delta) * math.Sqrt(float64(k))
+	if s < 1 {
+		s = 1
+	}
+	tau := make([]float64, k+1)
+	limit := int(float64(k) / s)
+	for d := 1; d < limit && d <= k; d++ {
+		tau[d] = s / (float64(d) * float64(k))
+	}
+	if limit >= 1 && limit <= k {
+		tau[limit] = s * math.Log(s/delta) / float64(k)
+	}
+
+	mu := make([]float64, k+1)
+	var sum float64
+	for d := 1; d <= k; d++ {
+		mu[d] = rho[d] + tau[d]
+		sum += mu[d]
+	}
+	for d := 1; d <= k; d++ {
+		mu[d] /= sum
+	}
+	return mu
+}
+
+func sampleDegree(rng *rand.Rand, dist []float64) int {
+	x := rng.Float64()
+	var cum float64
+	for d := 1; d < len(dist); d++ {
+		cum += dist[d]
+		if x <= cum {
+			return d
+		}
+	}
+	return len(dist) - 1
+}
+
+// ltNeighbors deterministically derives symbol i's degree and the set of
+// source indices it XORs together, using i as the PRNG seed.
+func ltNeighbors(i, k int, dist []float64) []int {
+	rng := rand.New(rand.NewSource(int64(i) + 1))
+	d := sampleDegree(rng, dist)
+	if d > k {
+		d = k
+	}
+	perm := rng.Perm(k)
+	return perm[:d]
+}
+
+func EncodeLT(payload []byte, symbolSize, parity int) ([][]byte, error) {
+	k := int(math.Ceil(float64(len(payload)) / float64(symbolSize)))
+	src := make([][]byte, k)
+	for i := 0; i < k; i++ {
+		start := i * symbolSize
+		end := start + symbolSize
+		if end > len(payload) {
+			end = len(payload)
+		}
+		shard := make([]byte, symbolSize)
+		copy(shard, payload[start:end])
+		src[i] = shard
+	}
+
+	dist := robustSolitonDist(k, ltRobustC, ltRobustDelta)
+	total := k + parity
+	out := make([][]byte, total)
+	for i := 0; i < total; i++ {
+		buf := make([]byte, symbolSize)
+		for _, s := range ltNeighbors(i, k, dist) {
+			for j := 0; j < symbolSize; j++ {
+				buf[j] ^= src[s][j]
+			}
+		}
+		out[i] = buf
+	}
+	return out, nil
+}
+
+// ltNode tracks one received encoded symbol's belief-propagation state: the
+// source indices it still depends on, and its running value after any
+// already-recovered sources have been XORed out.
+type ltNode struct {
+	remaining []int
+	value     []byte
+}
+
+// DecodeLT runs belief propagation over the received encoded symbols: it
+// repeatedly looks for a symbol whose neighbour set has exactly one
+// not-yet-recovered source left (effective degree 1), XORs that symbol's
+// value into the recovered slot, and then removes the newly-known source
+// from every other symbol's neighbour set. It terminates either once all k
+// source symbols are recovered, or once no degree-1 symbol remains. It
+// returns the number of source symbols still unrecovered when it stops,
+// which is 0 on success — belief propagation is a peeling decoder, so it
+// can recover some source symbols even when it ultimately stalls.
+func DecodeLT(shards [][]byte, lostIndices []int, k int) (int, error) {
+	dist := robustSolitonDist(k, ltRobustC, ltRobustDelta)
+
+	lost := make(map[int]bool, len(lostIndices))
+	for _, idx := range lostIndices {
+		lost[idx] = true
+	}
+
+	nodes := []*ltNode{}
+	for i, sh := range shards {
+		if sh == nil || lost[i] {
+			continue
+		}
+		n := &ltNode{
+			remaining: append([]int{}, ltNeighbors(i, k, dist)...),
+			value:     append([]byte{}, sh...),
+		}
+		nodes = append(nodes, n)
+	}
+
+	recovered := make([][]byte, k)
+	recoveredCount := 0
+
+	for {
+		progressed := false
+		for _, n := range nodes {
+			reduceNode(n, recovered)
+			if len(n.remaining) != 1 {
+				continue
+			}
+			src := n.remaining[0]
+			if recovered[src] != nil {
+				continue
+			}
+			recovered[src] = append([]byte{}, n.value...)
+			recoveredCount++
+			progressed = true
+		}
+		if recoveredCount == k || !progressed {
+			break
+		}
+	}
+
+	if recoveredCount < k {
+		return k - recoveredCount, fmt.Errorf("LT belief propagation stalled: recovered %d/%d source symbols", recoveredCount, k)
+	}
+	return 0, nil
+}
+
+// reduceNode XORs every already-recovered source's value out of a node's
+// running value and drops it from the neighbour list, so once only one
+// neighbour remains the node's value equals exactly that source's content.
+func reduceNode(n *ltNode, recovered [][]byte) {
+	remaining := n.remaining[:0]
+	for _, s := range n.remaining {
+		if recovered[s] != nil {
+			for j := range n.value {
+				n.value[j] ^= recovered[s][j]
+			}
+		} else {
+			remaining = append(remaining, s)
+		}
+	}
+	n.remaining = remaining
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"crypto/rand"
+	"fmt"
+	"math"
+	"runtime"
+	"sync"
+	"time"
+
+	rs "github.com/klauspost/reedsolomon"
+)
+
+//
+// ====================================================
+// Parallel / SIMD-aware throughput harness
+// ====================================================
+//
+// The per-op latency numbers `bench` reports hide how these libraries
+// actually behave once GOMAXPROCS > 1: klauspost/reedsolomon auto-vectorizes
+// with AVX2/AVX-512, and multiple streams get encoded concurrently in a real
+// QUIC stack. This sweeps GOMAXPROCS and the RS SIMD level and reports
+// aggregate throughput across concurrently-running encode jobs, which is
+// the number that actually matters for "how many streams can we FEC-encode
+// at once".
+
+const throughputJobDuration = 200 * time.Millisecond
+
+// rsOptionsForSIMD maps the -simd flag to the reedsolomon encoder options
+// that select (or disable) hardware acceleration.
+func rsOptionsForSIMD(mode string) []rs.Option {
+	switch mode {
+	case "none":
+		return []rs.Option{rs.WithAVX2(false), rs.WithSSSE3(false)}
+	case "avx2":
+		return []rs.Option{rs.WithAVX2(true)}
+	case "avx512":
+		return []rs.Option{rs.WithAVX512(true)}
+	default: // "auto": let the library probe the CPU
+		return nil
+	}
+}
+
+func encodeRSWithOptions(opts []rs.Option) func([]byte, int, int) ([][]byte, error) {
+	return func(payload []byte, symbolSize, parity int) ([][]byte, error) {
+		dataShards := int(math.Ceil(float64(len(payload)) / float64(symbolSize)))
+
+		enc, err := rs.New(dataShards, parity, opts...)
+		if err != nil {
+			return nil, err
+		}
+
+		shards := make([][]byte, dataShards+parity)
+		for i := 0; i < dataShards; i++ {
+			start := i * symbolSize
+			end := start + symbolSize
+			if end > len(payload) {
+				end = len(payload)
+			}
+			shard := make([]byte, symbolSize)
+			copy(shard, payload[start:end])
+			shards[i] = shard
+		}
+		for i := dataShards; i < dataShards+parity; i++ {
+			shards[i] = make([]byte, symbolSize)
+		}
+
+		if err := enc.Encode(shards); err != nil {
+			return nil, err
+		}
+		return shards, nil
+	}
+}
+
+// throughputBench runs `threads` goroutines concurrently, each repeatedly
+// calling encode for throughputJobDuration (after a short warmup pass so
+// the first, cold-cache call doesn't skew small-payload measurements), and
+// reports the aggregate bytes/sec across all of them.
+func throughputBench(algo string, encode func([]byte, int, int) ([][]byte, error),
+	N, S int, R float64, parity, threads int, simd string) Result {
+
+	payloadB := N * S
+	payload := make([]byte, payloadB)
+	_, _ = rand.Read(payload)
+
+	for i := 0; i < 3; i++ {
+		if _, err := encode(payload, S, parity); err != nil {
+			fmt.Printf("%s warmup error: %v\n", algo, err)
+			return Result{}
+		}
+	}
+
+	var wg sync.WaitGroup
+	counts := make([]int64, threads)
+	stop := make(chan struct{})
+
+	start := time.Now()
+	for t := 0; t < threads; t++ {
+		wg.Add(1)
+		go func(idx int) {
+			defer wg.Done()
+			var n int64
+			for {
+				select {
+				case <-stop:
+					counts[idx] = n
+					return
+				default:
+				}
+				if _, err := encode(payload, S, parity); err == nil {
+					n++
+				}
+			}
+		}(t)
+	}
+	time.Sleep(throughputJobDuration)
+	close(stop)
+	wg.Wait()
+	elapsedNs := time.Since(start).Nanoseconds()
+
+	var totalOps int64
+	for _, c := range counts {
+		totalOps += c
+	}
+	totalBytes := totalOps * int64(payloadB)
+	throughputGBs := float64(totalBytes) / (float64(elapsedNs) / 1e9) / 1e9
+
+	fmt.Printf("%-14s | Threads=%2d SIMD=%-6s | N=%4d S=%5d | Throughput=%.3f GB/s (%d ops)\n",
+		algo, threads, simd, N, S, throughputGBs, totalOps)
+
+	return Result{
+		Algo:          algo,
+		N:             N,
+		S:             S,
+		R:             R,
+		Parity:        parity,
+		PayloadB:      payloadB,
+		Threads:       threads,
+		SIMD:          simd,
+		ThroughputGBs: throughputGBs,
+	}
+}
+
+// runParallelThroughputSweep sweeps GOMAXPROCS from 1..NumCPU at a single
+// representative (N, S, R) point and reports aggregate throughput for every
+// algorithm at each thread count, restoring GOMAXPROCS to NumCPU afterwards.
+func runParallelThroughputSweep(simdMode string) []Result {
+	numCPU := runtime.NumCPU()
+	const N, S = 50, 512
+	const R = 20.0
+	parity := int(math.Ceil(float64(N) * R / 100.0))
+
+	rsEncode := encodeRSWithOptions(rsOptionsForSIMD(simdMode))
+
+	algos := []struct {
+		name   string
+		encode func([]byte, int, int) ([][]byte, error)
+	}{
+		{"XOR", EncodeXOR},
+		{"ReedSolomon", rsEncode},
+		{"RaptorQ", EncodeRaptorQ},
+		{"LT-Fountain", EncodeLT},
+		{"LDPC-Staircase", EncodeLDPCStaircase},
+	}
+
+	var results []Result
+	for threads := 1; threads <= numCPU; threads++ {
+		runtime.GOMAXPROCS(threads)
+		for _, a := range algos {
+			results = append(results, throughputBench(a.name, a.encode, N, S, R, parity, threads, simdMode))
+		}
+	}
+	runtime.GOMAXPROCS(numCPU)
+	return results
+}
@@ -0,0 +1,546 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/binary"
+	"encoding/pem"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"math/big"
+	mrand "math/rand"
+	"net"
+	"time"
+
+	"github.com/quic-go/quic-go"
+)
+
+//
+// ====================================================
+// QUIC datapath (real client/server over github.com/quic-go/quic-go)
+// ====================================================
+//
+// Unlike `bench`, which measures encode/decode CPU time in-process, `quic`
+// pushes FEC-coded shards through an actual QUIC connection on loopback: the
+// sender encodes each application block and writes data+parity shards as
+// unreliable DATAGRAM frames (RFC 9221), the receiver reconstructs the block
+// from whatever arrives and ACKs over a regular stream, and a netem-style
+// wrapper around the loopback socket drops/delays datagrams so the measured
+// GoodputMbps and RecoverySuccessRate reflect a real QUIC stack under loss.
+//
+
+const quicALPN = "quic-fec-estimation"
+
+// shardHeader is the fixed-size prefix written ahead of each shard payload
+// on a DATAGRAM frame.
+type shardHeader struct {
+	BlockID uint32
+	Index   uint16
+	Total   uint16
+	DataN   uint16
+}
+
+const shardHeaderLen = 4 + 2 + 2 + 2
+
+func encodeShardHeader(h shardHeader) []byte {
+	buf := make([]byte, shardHeaderLen)
+	binary.BigEndian.PutUint32(buf[0:4], h.BlockID)
+	binary.BigEndian.PutUint16(buf[4:6], h.Index)
+	binary.BigEndian.PutUint16(buf[6:8], h.Total)
+	binary.BigEndian.PutUint16(buf[8:10], h.DataN)
+	return buf
+}
+
+func decodeShardHeader(buf []byte) shardHeader {
+	return shardHeader{
+		BlockID: binary.BigEndian.Uint32(buf[0:4]),
+		Index:   binary.BigEndian.Uint16(buf[4:6]),
+		Total:   binary.BigEndian.Uint16(buf[6:8]),
+		DataN:   binary.BigEndian.Uint16(buf[8:10]),
+	}
+}
+
+// blockAnnounce is the first thing the sender writes on a block's control
+// stream; it opens the stream (QUIC streams carry no data, and thus aren't
+// visible to AcceptStream, until something is written to them) and tells
+// the receiver how many shards to expect before any datagrams arrive.
+type blockAnnounce struct {
+	BlockID uint32
+	Total   uint16
+	DataN   uint16
+}
+
+const blockAnnounceLen = 4 + 2 + 2
+
+func encodeAnnounce(a blockAnnounce) []byte {
+	buf := make([]byte, blockAnnounceLen)
+	binary.BigEndian.PutUint32(buf[0:4], a.BlockID)
+	binary.BigEndian.PutUint16(buf[4:6], a.Total)
+	binary.BigEndian.PutUint16(buf[6:8], a.DataN)
+	return buf
+}
+
+func decodeAnnounce(buf []byte) blockAnnounce {
+	return blockAnnounce{
+		BlockID: binary.BigEndian.Uint32(buf[0:4]),
+		Total:   binary.BigEndian.Uint16(buf[4:6]),
+		DataN:   binary.BigEndian.Uint16(buf[6:8]),
+	}
+}
+
+// blockAck is sent by the receiver back to the sender over a stream once a
+// block's datagram window has closed, reporting which shard indices never
+// arrived so the sender can selectively retransmit them.
+type blockAck struct {
+	BlockID   uint32
+	Recovered bool
+	Final     bool // no further retransmission rounds will follow
+	Missing   []uint16
+}
+
+func encodeAck(a blockAck) []byte {
+	buf := make([]byte, 4+1+1+2+2*len(a.Missing))
+	binary.BigEndian.PutUint32(buf[0:4], a.BlockID)
+	if a.Recovered {
+		buf[4] = 1
+	}
+	if a.Final {
+		buf[5] = 1
+	}
+	binary.BigEndian.PutUint16(buf[6:8], uint16(len(a.Missing)))
+	for i, idx := range a.Missing {
+		binary.BigEndian.PutUint16(buf[8+2*i:10+2*i], idx)
+	}
+	return buf
+}
+
+// writeFramed writes a uint16 length prefix followed by payload. QUIC
+// streams carry no message boundaries, so anything whose size isn't fixed
+// ahead of time (like blockAck, which grows with the missing-shard count)
+// needs its own length to be read back reliably.
+func writeFramed(w io.Writer, payload []byte) error {
+	var lenBuf [2]byte
+	binary.BigEndian.PutUint16(lenBuf[:], uint16(len(payload)))
+	if _, err := w.Write(lenBuf[:]); err != nil {
+		return err
+	}
+	_, err := w.Write(payload)
+	return err
+}
+
+// readFramed reads back a message written by writeFramed, using io.ReadFull
+// throughout since a QUIC stream Read can return fewer bytes than requested.
+func readFramed(r io.Reader) ([]byte, error) {
+	var lenBuf [2]byte
+	if _, err := io.ReadFull(r, lenBuf[:]); err != nil {
+		return nil, err
+	}
+	buf := make([]byte, binary.BigEndian.Uint16(lenBuf[:]))
+	if _, err := io.ReadFull(r, buf); err != nil {
+		return nil, err
+	}
+	return buf, nil
+}
+
+func decodeAck(buf []byte) blockAck {
+	a := blockAck{
+		BlockID:   binary.BigEndian.Uint32(buf[0:4]),
+		Recovered: buf[4] == 1,
+		Final:     buf[5] == 1,
+	}
+	n := int(binary.BigEndian.Uint16(buf[6:8]))
+	for i := 0; i < n; i++ {
+		a.Missing = append(a.Missing, binary.BigEndian.Uint16(buf[8+2*i:10+2*i]))
+	}
+	return a
+}
+
+// lossyPacketConn wraps a net.PacketConn with a netem-compatible loss and
+// latency layer so a loopback UDP socket can stand in for a real lossy QUIC
+// path: outbound writes are dropped with probability LossProb, and surviving
+// writes are delayed by Latency plus up to Jitter of extra random delay.
+type lossyPacketConn struct {
+	net.PacketConn
+	LossProb float64
+	Latency  time.Duration
+	Jitter   time.Duration
+	rng      *mrand.Rand
+}
+
+func (c *lossyPacketConn) WriteTo(p []byte, addr net.Addr) (int, error) {
+	if c.rng.Float64() < c.LossProb {
+		return len(p), nil // dropped, as a lossy link would do
+	}
+	buf := append([]byte(nil), p...)
+	delay := c.Latency
+	if c.Jitter > 0 {
+		delay += time.Duration(c.rng.Int63n(int64(c.Jitter)))
+	}
+	if delay <= 0 {
+		return c.PacketConn.WriteTo(buf, addr)
+	}
+	time.AfterFunc(delay, func() {
+		c.PacketConn.WriteTo(buf, addr)
+	})
+	return len(p), nil
+}
+
+func generateTLSConfig() (*tls.Config, error) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, err
+	}
+	template := x509.Certificate{SerialNumber: big.NewInt(1)}
+	certDER, err := x509.CreateCertificate(rand.Reader, &template, &template, &key.PublicKey, key)
+	if err != nil {
+		return nil, err
+	}
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certDER})
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		return nil, err
+	}
+	return &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		NextProtos:   []string{quicALPN},
+	}, nil
+}
+
+// quicFlags holds the `quic` subcommand's configuration; it mirrors the
+// (N, S, R, algo) dimensions `bench` sweeps, but for a single fixed point
+// since each run drives a real connection.
+type quicFlags struct {
+	algo    string
+	n       int
+	s       int
+	parity  int
+	blocks  int
+	lossP   float64
+	latency time.Duration
+	jitter  time.Duration
+	maxRetx int
+}
+
+func runQuicCmd(args []string) {
+	fs := flag.NewFlagSet("quic", flag.ExitOnError)
+	algo := fs.String("algo", "rs", "FEC scheme: xor, rs, or raptorq")
+	n := fs.Int("n", 20, "data symbols per block")
+	s := fs.Int("s", 250, "bytes per symbol")
+	r := fs.Float64("r", 20, "redundancy percent")
+	blocks := fs.Int("blocks", 50, "number of application blocks to send")
+	lossP := fs.Float64("loss", 0.05, "loopback datagram loss probability")
+	latency := fs.Duration("latency", 10*time.Millisecond, "one-way base latency")
+	jitter := fs.Duration("jitter", 2*time.Millisecond, "one-way latency jitter")
+	maxRetx := fs.Int("max-retx", 2, "max retransmission rounds per block")
+	fs.Parse(args)
+
+	qf := quicFlags{
+		algo:    *algo,
+		n:       *n,
+		s:       *s,
+		parity:  int(math.Ceil(float64(*n) * *r / 100.0)),
+		blocks:  *blocks,
+		lossP:   *lossP,
+		latency: *latency,
+		jitter:  *jitter,
+		maxRetx: *maxRetx,
+	}
+
+	result, err := runQuicFlow(qf)
+	if err != nil {
+		fmt.Println("quic flow error:", err)
+		return
+	}
+
+	fmt.Printf("%-12s | N=%4d S=%5d Parity=%3d | Goodput=%.3f Mbps | Recovery=%.1f%% | Retx=%d\n",
+		result.Algo, result.N, result.S, result.Parity, result.GoodputMbps, result.RecoverySuccessRate*100, result.RetxCount)
+
+	writeCSV("results_quic.csv", []Result{result})
+}
+
+// runQuicFlow spins up a server and client over a loopback UDP pair, sends
+// `blocks` application blocks FEC-coded with the configured scheme, and
+// returns the aggregate Result.
+func runQuicFlow(qf quicFlags) (Result, error) {
+	tlsConf, err := generateTLSConfig()
+	if err != nil {
+		return Result{}, err
+	}
+
+	serverUDP, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		return Result{}, err
+	}
+	defer serverUDP.Close()
+
+	clientUDP, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		return Result{}, err
+	}
+	lossyClientConn := &lossyPacketConn{
+		PacketConn: clientUDP,
+		LossProb:   qf.lossP,
+		Latency:    qf.latency,
+		Jitter:     qf.jitter,
+		rng:        mrand.New(mrand.NewSource(time.Now().UnixNano())),
+	}
+	defer clientUDP.Close()
+
+	quicConf := &quic.Config{EnableDatagrams: true}
+
+	ln, err := quic.Listen(serverUDP, tlsConf, quicConf)
+	if err != nil {
+		return Result{}, err
+	}
+	defer ln.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	serverDone := make(chan error, 1)
+	var serverStats serverResult
+	go func() {
+		stats, err := runQuicServer(ctx, ln, qf)
+		serverStats = stats
+		serverDone <- err
+	}()
+
+	clientTLSConf := &tls.Config{InsecureSkipVerify: true, NextProtos: []string{quicALPN}}
+	conn, err := quic.Dial(ctx, lossyClientConn, serverUDP.LocalAddr(), clientTLSConf, quicConf)
+	if err != nil {
+		return Result{}, err
+	}
+	defer conn.CloseWithError(0, "done")
+
+	clientStats, err := runQuicClient(ctx, conn, qf)
+	if err != nil {
+		return Result{}, err
+	}
+
+	if err := <-serverDone; err != nil {
+		return Result{}, err
+	}
+
+	payloadB := qf.n * qf.s
+	recoveryRate := 0.0
+	if qf.blocks > 0 {
+		recoveryRate = float64(serverStats.recoveredBlocks) / float64(qf.blocks)
+	}
+	goodputMbps := 0.0
+	if clientStats.elapsed > 0 {
+		goodputMbps = float64(serverStats.recoveredBlocks*payloadB*8) / clientStats.elapsed.Seconds() / 1e6
+	}
+
+	return Result{
+		Algo:                algoLabel(qf.algo),
+		N:                   qf.n,
+		S:                   qf.s,
+		R:                   float64(qf.parity) / float64(qf.n) * 100,
+		Parity:              qf.parity,
+		PayloadB:            payloadB,
+		LossModel:           "uniform-datagram",
+		LossParams:          fmt.Sprintf("p=%.3f latency=%s jitter=%s", qf.lossP, qf.latency, qf.jitter),
+		RecoverySuccessRate: recoveryRate,
+		ResidualLossRate:    1 - recoveryRate,
+		GoodputMbps:         goodputMbps,
+		RetxCount:           clientStats.retxCount,
+	}, nil
+}
+
+func algoLabel(algo string) string {
+	switch algo {
+	case "xor":
+		return "XOR"
+	case "raptorq":
+		return "RaptorQ"
+	default:
+		return "ReedSolomon"
+	}
+}
+
+type serverResult struct {
+	recoveredBlocks int
+}
+
+// runQuicServer accepts the client's connection, then for each block reads
+// a control stream announcing the block's shard layout, collects datagrams
+// for a short window, attempts FEC reconstruction, and ACKs the result
+// (plus any still-missing shard indices) back on the same stream.
+func runQuicServer(ctx context.Context, ln *quic.Listener, qf quicFlags) (serverResult, error) {
+	conn, err := ln.Accept(ctx)
+	if err != nil {
+		return serverResult{}, err
+	}
+
+	stats := serverResult{}
+	for b := 0; b < qf.blocks; b++ {
+		stream, err := conn.AcceptStream(ctx)
+		if err != nil {
+			return stats, err
+		}
+
+		announceBuf := make([]byte, blockAnnounceLen)
+		if _, err := io.ReadFull(stream, announceBuf); err != nil {
+			stream.Close()
+			return stats, err
+		}
+		announce := decodeAnnounce(announceBuf)
+
+		total := int(announce.Total)
+		shards := make([][]byte, total)
+		received := 0
+
+		for round := 0; ; round++ {
+			windowCtx, cancel := context.WithTimeout(ctx, 200*time.Millisecond)
+			for received < total {
+				dgram, err := conn.ReceiveDatagram(windowCtx)
+				if err != nil {
+					break
+				}
+				if len(dgram) < shardHeaderLen {
+					continue
+				}
+				h := decodeShardHeader(dgram[:shardHeaderLen])
+				if int(h.BlockID) != b {
+					continue
+				}
+				if shards[h.Index] == nil {
+					shards[h.Index] = append([]byte(nil), dgram[shardHeaderLen:]...)
+					received++
+				}
+			}
+			cancel()
+
+			var missing []uint16
+			for i, sh := range shards {
+				if sh == nil {
+					missing = append(missing, uint16(i))
+				}
+			}
+
+			recovered := decodeBlock(qf.algo, shards, missing, qf.n, qf.s, qf.parity) == nil
+			final := recovered || round >= qf.maxRetx
+
+			ack := encodeAck(blockAck{BlockID: uint32(b), Recovered: recovered, Final: final, Missing: missing})
+			if err := writeFramed(stream, ack); err != nil {
+				stream.Close()
+				return stats, err
+			}
+			if final {
+				if recovered {
+					stats.recoveredBlocks++
+				}
+				break
+			}
+		}
+		stream.Close()
+	}
+
+	return stats, nil
+}
+
+func decodeBlock(algo string, shards [][]byte, missing []uint16, n, s, parity int) error {
+	lost := make([]int, len(missing))
+	for i, m := range missing {
+		lost[i] = int(m)
+	}
+	var err error
+	switch algo {
+	case "xor":
+		_, err = DecodeXOR(shards, lost, n)
+	case "raptorq":
+		_, err = DecodeRaptorQ(shards, s, n*s)
+	default:
+		_, err = DecodeRS(shards, parity)
+	}
+	return err
+}
+
+type clientResult struct {
+	elapsed   time.Duration
+	retxCount int
+}
+
+// runQuicClient encodes and sends each block as data+parity datagrams, then
+// waits for the receiver's ACK on a per-block stream, retransmitting the
+// shards the ACK reports missing (up to MaxRetx rounds) before moving on.
+func runQuicClient(ctx context.Context, conn quic.Connection, qf quicFlags) (clientResult, error) {
+	start := time.Now()
+	retx := 0
+
+	for b := 0; b < qf.blocks; b++ {
+		payload := make([]byte, qf.n*qf.s)
+		if _, err := rand.Read(payload); err != nil {
+			return clientResult{}, err
+		}
+
+		shards, err := encodeBlock(qf.algo, payload, qf.s, qf.parity)
+		if err != nil {
+			return clientResult{}, err
+		}
+		total := len(shards)
+
+		stream, err := conn.OpenStreamSync(ctx)
+		if err != nil {
+			return clientResult{}, err
+		}
+		if _, err := stream.Write(encodeAnnounce(blockAnnounce{BlockID: uint32(b), Total: uint16(total), DataN: uint16(qf.n)})); err != nil {
+			return clientResult{}, err
+		}
+
+		sendShards := func(indices []uint16) error {
+			for _, idx := range indices {
+				h := encodeShardHeader(shardHeader{BlockID: uint32(b), Index: idx, Total: uint16(total), DataN: uint16(qf.n)})
+				if err := conn.SendDatagram(append(h, shards[idx]...)); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+
+		all := make([]uint16, total)
+		for i := range all {
+			all[i] = uint16(i)
+		}
+		if err := sendShards(all); err != nil {
+			return clientResult{}, err
+		}
+
+		for {
+			ackBuf, err := readFramed(stream)
+			if err != nil {
+				break
+			}
+			ack := decodeAck(ackBuf)
+			if !ack.Recovered && len(ack.Missing) > 0 {
+				retx += len(ack.Missing)
+				if err := sendShards(ack.Missing); err != nil {
+					return clientResult{}, err
+				}
+			}
+			if ack.Final {
+				break
+			}
+		}
+		stream.Close()
+	}
+
+	return clientResult{elapsed: time.Since(start), retxCount: retx}, nil
+}
+
+func encodeBlock(algo string, payload []byte, s, parity int) ([][]byte, error) {
+	switch algo {
+	case "xor":
+		return EncodeXOR(payload, s, parity)
+	case "raptorq":
+		return EncodeRaptorQ(payload, s, parity)
+	default:
+		return EncodeRS(payload, s, parity)
+	}
+}